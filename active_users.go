@@ -0,0 +1,52 @@
+/* active_users.go tracks when each chatter was last seen, so the
+ * loyalty system can pay out to everyone active within a recent window
+ * without re-scanning chat history.
+ */
+
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// ActiveUserTracker records the last-seen time for each chatter.
+type ActiveUserTracker struct {
+	mu       sync.Mutex
+	lastSeen map[string]time.Time
+}
+
+// NewActiveUserTracker builds an empty tracker.
+func NewActiveUserTracker() *ActiveUserTracker {
+	return &ActiveUserTracker{lastSeen: make(map[string]time.Time)}
+}
+
+// Touch marks username as active right now.
+func (a *ActiveUserTracker) Touch(username string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lastSeen[strings.ToLower(username)] = time.Now()
+}
+
+// Remove stops tracking username, e.g. once they've PARTed the channel.
+func (a *ActiveUserTracker) Remove(username string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.lastSeen, strings.ToLower(username))
+}
+
+// Active returns everyone touched within the last window.
+func (a *ActiveUserTracker) Active(window time.Duration) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cutoff := time.Now().Add(-window)
+	var active []string
+	for username, seen := range a.lastSeen {
+		if seen.After(cutoff) {
+			active = append(active, username)
+		}
+	}
+	return active
+}
@@ -0,0 +1,146 @@
+/* automessage.go replaces the old single-interval Automessage() loop
+ * with a scheduler that can hold many independently-configured
+ * automessages: cron-scheduled, gated on a minimum number of chat lines
+ * and/or a minimum wall-clock gap since they last fired, optionally
+ * gated on the channel actually being live, and rendered through
+ * text/template so streamers can reference the current viewer count and
+ * game.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+// AutoMessage is one scheduled message entry.
+type AutoMessage struct {
+	Channel         string
+	Message         string
+	UseAction       bool
+	Cron            string
+	MessageInterval int64
+	TimeInterval    time.Duration
+	OnlyOnLive      bool
+
+	schedule    Schedule
+	lastSent    time.Time
+	linesAtSent int64
+	firedMinute string // "YYYY-MM-DD HH:MM" this last fired, so a once-a-second
+	// tick doesn't refire for all 60 seconds of a matching minute
+}
+
+// AutoMessageScheduler evaluates every registered AutoMessage once a
+// second and emits it to chat once its cron schedule, its thresholds,
+// and (if set) the live gate all allow it.
+type AutoMessageScheduler struct {
+	bot       *Bot
+	helix     *HelixClient
+	messages  []*AutoMessage
+	lineCount atomic.Int64 // written from OnPrivateMessage, read/written from tick()
+}
+
+// NewAutoMessageScheduler builds a scheduler bound to bot. helix may be
+// nil as long as no registered AutoMessage sets OnlyOnLive.
+func NewAutoMessageScheduler(bot *Bot, helix *HelixClient) *AutoMessageScheduler {
+	return &AutoMessageScheduler{bot: bot, helix: helix}
+}
+
+// Add parses am's cron schedule and registers it with the scheduler.
+func (s *AutoMessageScheduler) Add(am *AutoMessage) error {
+	sched, err := ParseSchedule(am.Cron)
+	if err != nil {
+		return fmt.Errorf("automessage %q: %w", am.Message, err)
+	}
+	am.schedule = sched
+	s.messages = append(s.messages, am)
+	return nil
+}
+
+// CountLine should be called from OnPrivateMessage so MessageInterval
+// gating has a running count of chat lines to compare against.
+func (s *AutoMessageScheduler) CountLine() {
+	s.lineCount.Add(1)
+}
+
+// Run evaluates every AutoMessage once a second until the process
+// exits; call it in a goroutine, the same way the old Automessage() was.
+func (s *AutoMessageScheduler) Run() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		s.tick(now)
+	}
+}
+
+func (s *AutoMessageScheduler) tick(now time.Time) {
+	minuteKey := now.Format("2006-01-02 15:04")
+	for _, am := range s.messages {
+		if !am.schedule.Matches(now) || am.firedMinute == minuteKey {
+			continue
+		}
+		lineCount := s.lineCount.Load()
+		if am.MessageInterval > 0 && lineCount-am.linesAtSent < am.MessageInterval {
+			continue
+		}
+		if am.TimeInterval > 0 && !am.lastSent.IsZero() && now.Sub(am.lastSent) < am.TimeInterval {
+			continue
+		}
+
+		var stream StreamInfo
+		if am.OnlyOnLive {
+			if s.helix == nil {
+				continue
+			}
+			info, err := s.helix.Stream(am.Channel)
+			if err != nil || !info.Live {
+				continue
+			}
+			stream = info
+		}
+
+		text, err := renderAutoMessage(am, stream)
+		if err != nil {
+			fmt.Printf("automessage: %v\n", err)
+			continue
+		}
+		if am.UseAction {
+			text = "\x01ACTION " + text + "\x01"
+		}
+
+		s.bot.Message(text)
+		am.lastSent = now
+		am.linesAtSent = lineCount
+		am.firedMinute = minuteKey
+	}
+}
+
+// renderAutoMessage executes am.Message as a text/template, exposing
+// .Channel, .Viewers, and .Game resolved from stream (the zero value
+// when the entry isn't live-gated or the channel is offline).
+func renderAutoMessage(am *AutoMessage, stream StreamInfo) (string, error) {
+	tmpl, err := template.New("automessage").Parse(am.Message)
+	if err != nil {
+		return "", err
+	}
+
+	data := struct {
+		Channel string
+		Viewers int
+		Game    string
+	}{
+		Channel: am.Channel,
+		Viewers: stream.Viewers,
+		Game:    stream.Game,
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,112 @@
+/* automessage_config.go hand-rolls just enough of a YAML reader/writer to
+ * persist []*AutoMessage next to the binary: a top-level list of flat,
+ * string-keyed mappings. It intentionally doesn't attempt full YAML (no
+ * nesting, anchors, multi-line strings) since that's all this config
+ * needs, and keeps the bot dependency-free.
+ */
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoadAutoMessages reads the automessage list stored as YAML at path.
+func LoadAutoMessages(path string) ([]*AutoMessage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var messages []*AutoMessage
+	var current *AutoMessage
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		trimmed := strings.TrimSpace(scanner.Text())
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "- ") {
+			current = &AutoMessage{}
+			messages = append(messages, current)
+			trimmed = strings.TrimPrefix(trimmed, "- ")
+		}
+		if current == nil {
+			continue
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+
+		if err := setAutoMessageField(current, key, value); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func setAutoMessageField(am *AutoMessage, key, value string) error {
+	switch key {
+	case "channel":
+		am.Channel = value
+	case "message":
+		am.Message = value
+	case "cron":
+		am.Cron = value
+	case "useAction":
+		am.UseAction = value == "true"
+	case "onlyOnLive":
+		am.OnlyOnLive = value == "true"
+	case "messageInterval":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("messageInterval: %w", err)
+		}
+		am.MessageInterval = n
+	case "timeInterval":
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("timeInterval: %w", err)
+		}
+		am.TimeInterval = d
+	default:
+		return fmt.Errorf("unknown automessage field %q", key)
+	}
+	return nil
+}
+
+// SaveAutoMessages writes messages to path as YAML, atomically (write a
+// temp file then rename) so a crash mid-write can't corrupt the config.
+func SaveAutoMessages(path string, messages []*AutoMessage) error {
+	var b strings.Builder
+	for _, am := range messages {
+		fmt.Fprintf(&b, "- channel: %q\n", am.Channel)
+		fmt.Fprintf(&b, "  message: %q\n", am.Message)
+		fmt.Fprintf(&b, "  cron: %q\n", am.Cron)
+		fmt.Fprintf(&b, "  useAction: %t\n", am.UseAction)
+		fmt.Fprintf(&b, "  onlyOnLive: %t\n", am.OnlyOnLive)
+		fmt.Fprintf(&b, "  messageInterval: %d\n", am.MessageInterval)
+		fmt.Fprintf(&b, "  timeInterval: %s\n", am.TimeInterval)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
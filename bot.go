@@ -1,6 +1,7 @@
 /* bot.go defines and builds a bot struct from use in a twitch.tv chat
  * implemented:
- *		- connects to twitch.tv
+ *		- connects to twitch.tv, requesting the tags/commands/membership caps
+ *		- event-driven dispatch of PRIVMSG/JOIN/PART/CLEARCHAT/USERNOTICE/WHISPER
  *		- can print from console to twitch chat
  *		- concurrency via goroutines
  *		- prints to chat and console when new user joins
@@ -8,8 +9,6 @@
  *		- detects if a message has a website address and times out if so
  *		- exits from console with !quit
  * unimplemented:
- *		x seperate main and Bot struct into two files
- *		x react to certain phrases in chat
  *		x ability to detect spam from other users
  * by: Aaron Santucci
  * for: CS 214 Section A Spring 2017
@@ -36,14 +35,23 @@ type Bot struct {
 	port           string
 	nickname       string
 	channel        string
-	automsg        string
-	autoMsgCount   int
 	conn           net.Conn
-	mods           map[string]bool
-	userLastMsg    map[string]int64
+	userLastMsg    *LastMsgTracker
 	lastMsg        int64
 	maxMsgTime     int64
 	userMaxLastMsg int
+	permits        *PermitStore
+	urlPolicy      *URLPolicy
+	loyalty        *LoyaltySystem
+	moderation     *ModerationStore
+	shutdownHooks  []func()
+
+	onPrivateMessage []PrivateMessageHandler
+	onJoin           []JoinHandler
+	onPart           []PartHandler
+	onClearChat      []ClearChatHandler
+	onUserNotice     []UserNoticeHandler
+	onWhisper        []WhisperHandler
 }
 
 /* the constructor for the Bot struct
@@ -51,19 +59,17 @@ type Bot struct {
  */
 func NewBot() *Bot {
 	return &Bot{
-		server:   "irc.twitch.tv",
-		port:     "6667",
-		nickname: "adefault", // the name of the bot
-		channel:  "adefault", // the name of the channel
-		mods:     make(map[string]bool),
-		// *message stuff*
-		automsg:        "This is a default test automessage",
-		autoMsgCount:   5,
+		server:         "irc.twitch.tv",
+		port:           "6667",
+		nickname:       "adefault", // the name of the bot
+		channel:        "adefault", // the name of the channel
 		conn:           nil,
 		lastMsg:        0,
 		maxMsgTime:     3,
-		userLastMsg:    make(map[string]int64),
+		userLastMsg:    NewLastMsgTracker(),
 		userMaxLastMsg: 2,
+		permits:        NewPermitStore(),
+		urlPolicy:      NewURLPolicy(nil, nil),
 	}
 }
 
@@ -83,6 +89,16 @@ func (bot *Bot) Connect() {
 	fmt.Printf("Connected to " + bot.server + "\n")
 }
 
+/* RequestCapabilities asks Twitch for the IRCv3 capabilities the bot
+ * depends on: "tags" puts badges/mod/user-id/etc on every message,
+ * "commands" enables CLEARCHAT/USERNOTICE/WHISPER/HOSTTARGET/RECONNECT,
+ * and "membership" brings back JOIN/PART/NAMES notifications. Without
+ * these, Twitch silently degrades to the old untagged protocol.
+ */
+func (bot *Bot) RequestCapabilities() {
+	bot.conn.Write([]byte("CAP REQ :twitch.tv/tags twitch.tv/commands twitch.tv/membership\r\n"))
+}
+
 /* Message() puts a string to Twitch chat
  * @param: message string, the string to be put in chat
  */
@@ -101,14 +117,10 @@ func (bot *Bot) Message(message string) {
 
 }
 
-/* Automessage() starts an infinite loop that prints a Bot type's automsg variable
- *		unless it has exceeded autoMsgCount, the cooldown for messaging chat
- */
-func (bot *Bot) Automessage() {
-	for {
-		time.Sleep(time.Duration(bot.autoMsgCount) * time.Minute)
-		bot.Message(bot.automsg)
-	}
+// OnShutdown registers fn to run when the bot is shutting down (via
+// "!quit"), e.g. so subsystems can close their own connections first.
+func (bot *Bot) OnShutdown(fn func()) {
+	bot.shutdownHooks = append(bot.shutdownHooks, fn)
 }
 
 /* ConsoleInput parses text from the commandline.
@@ -120,83 +132,197 @@ func (bot *Bot) ConsoleInput() {
 		text, _ := buffer.ReadString('\n')
 		if strings.HasPrefix(text, "!quit") {
 			bot.Message("Shutting down bot :(")
-			bot.conn.Close()
-			os.Exit(0)
+			bot.Shutdown()
 		} else if strings.HasPrefix(text, "!") {
-			bot.ParseCommand(strings.Replace(bot.channel, "#", "", 1), text)
+			bot.ParseCommand(Message{
+				User:   strings.Replace(bot.channel, "#", "", 1),
+				Tags:   map[string]string{"mod": "1"},
+				Params: []string{bot.channel, text},
+			})
 		} else if text != "" {
 			bot.Message(text)
 		}
 	}
 }
 
-/* isModerator checks if a username is in a list of mods or the channel owner
- * @param: username string, the username to be checked if a moderater
- * @return: true, if username is a moderator
- *			false, if username is not a moderator
+/* isModerator reports whether msg's sender may run mod-only commands:
+ * the channel owner, a Twitch channel moderator (the "mod=1"/broadcaster
+ * badge tags, once the IRCv3 parser has populated them), or a user
+ * granted bot-side operator status via "!op" (see ModerationStore).
+ * @param: msg Message, the message whose sender is being checked
+ * @return: true, if the sender is a moderator
+ *			false, if the sender is not a moderator
  */
-func (bot *Bot) isModerator(username string) bool {
+func (bot *Bot) isModerator(msg Message) bool {
 	yourChannel := strings.Replace(bot.channel, "#", "", 1)
-	if bot.mods[username] == true || username == yourChannel {
+	if msg.User == yourChannel || msg.IsMod() {
 		return true
 	}
-	return false
+	return bot.moderation != nil && bot.moderation.IsOperator(msg.User)
 }
 
-/* timeout(), ban(), and unban() "punishment" functions all receive a username
- *		and call a Twitch function from Twitch chat to act on username
- * @param: username string, the user being timed out, banned, or unbanned respectively
+// minTimeoutSeconds and maxTimeoutSeconds are the bounds Twitch enforces
+// on "/timeout <user> <seconds>".
+const (
+	minTimeoutSeconds = 1
+	maxTimeoutSeconds = 1209600
+)
+
+/* Timeout times username out for duration (clamped to the 1s-1209600s
+ * range Twitch allows), giving reason in the chat command so it shows
+ * up in the moderation log.
+ * @param: username string, the user being timed out
+ * @param: duration time.Duration, how long to time them out for
+ * @param: reason string, the reason recorded with the timeout
  */
-func (bot *Bot) timeout(username string) {
-	if bot.isModerator(username) {
-		fmt.Printf("Unmod before punishing")
-		return // exit function if user is a moderator
-	} else {
-		bot.Message("/timeout " + username)
-		bot.Message("Timed out user: " + username)
+func (bot *Bot) Timeout(username string, duration time.Duration, reason string) {
+	seconds := int64(duration.Seconds())
+	if seconds < minTimeoutSeconds {
+		seconds = minTimeoutSeconds
+	} else if seconds > maxTimeoutSeconds {
+		seconds = maxTimeoutSeconds
 	}
+	bot.Message(fmt.Sprintf("/timeout %s %d %s", username, seconds, reason))
 }
 
-func (bot *Bot) ban(username string) {
-	if bot.isModerator(username) {
-		fmt.Printf("Unmod before punishing")
-		return // exit function if user is a moderator
-	} else {
-		bot.Message("/ban " + username)
-		bot.Message("Banned user: " + username)
+// Ban permanently bans username, recording reason in the chat command
+// and in the bot's own persisted ban record.
+func (bot *Bot) Ban(username, reason string) {
+	bot.Message(fmt.Sprintf("/ban %s %s", username, reason))
+	if bot.moderation == nil {
+		return
+	}
+	if err := bot.moderation.RecordBan(username, reason); err != nil {
+		fmt.Printf("moderation: %v\n", err)
 	}
 }
 
-func (bot *Bot) unban(username string) {
-	if bot.isModerator(username) {
-		fmt.Printf("Unmod before punishing")
-		return // exit function if user is a moderator
-	} else {
-		bot.Message("/unban " + username)
-		bot.Message("Unbanned user: " + username)
+// Unban lifts a ban on username and removes it from the bot's own
+// persisted ban record.
+func (bot *Bot) Unban(username string) {
+	bot.Message("/unban " + username)
+	if bot.moderation == nil {
+		return
+	}
+	if err := bot.moderation.RecordUnban(username); err != nil {
+		fmt.Printf("moderation: %v\n", err)
 	}
 }
 
-/* ParseCommand() is called by a message beginning with ":!" (if twitch chat)
- *		or "!" (if command line) and finds and call the appropriate command function
+// Shutdown gracefully disconnects the bot: parts the channel, runs any
+// registered shutdown hooks (flushing stores, closing bridges), closes
+// the connection, and exits.
+func (bot *Bot) Shutdown() {
+	bot.conn.Write([]byte("PART " + bot.channel + "\r\n"))
+	for _, hook := range bot.shutdownHooks {
+		hook()
+	}
+	bot.conn.Close()
+	os.Exit(0)
+}
+
+// WhisperTo sends message to username as a Twitch whisper.
+func (bot *Bot) WhisperTo(username, message string) {
+	bot.Message("/w " + username + " " + message)
+}
+
+// KickAll times out, for one second, every user the bot has seen post a
+// message since it started. Useful for forcing everyone's client to
+// reload chat after a feature rollout.
+func (bot *Bot) KickAll() {
+	for _, username := range bot.userLastMsg.Usernames() {
+		bot.Timeout(username, time.Second, "forced reload")
+	}
+}
+
+/* ParseCommand() is called with the structured Message that triggered it
+ *		(chat or console) and finds and calls the appropriate command function.
+ *		Taking the full Message, rather than just the command text, lets
+ *		commands look up the invoker's user-id/room-id/display-name tags.
+ *		Unlike the punishment commands, the loyalty commands !balance/!top
+ *		and any configured redeemable are open to every chatter, not just mods.
  */
-func (bot *Bot) ParseCommand(user string, theCommand string) {
-	if bot.isModerator(user) {
-		command := strings.ToLower(theCommand)
-		userinfo := strings.Split(theCommand, " ")
-
-		if strings.HasPrefix(command, ":!timeout") || strings.HasPrefix(command, "!timeout") {
-			bot.timeout(userinfo[1])
-		} else if strings.HasPrefix(command, ":!ban") || strings.HasPrefix(command, "!ban") {
-			bot.ban(userinfo[1])
-		} else if strings.HasPrefix(command, ":!unban") || strings.HasPrefix(command, "!unban") {
-			bot.unban(userinfo[1])
+func (bot *Bot) ParseCommand(msg Message) {
+	theCommand := strings.TrimPrefix(msg.Text(), ":")
+	fields := strings.Fields(theCommand)
+	if len(fields) == 0 {
+		return
+	}
+	name := strings.ToLower(fields[0])
+	args := fields[1:]
+
+	switch name {
+	case "!timeout":
+		bot.requireMod(msg, func() { bot.handleTimeoutCommand(args) })
+	case "!ban":
+		bot.requireMod(msg, func() {
+			reason := ""
+			if len(args) > 1 {
+				reason = strings.Join(args[1:], " ")
+			}
+			bot.Ban(args[0], reason)
+		})
+	case "!unban":
+		bot.requireMod(msg, func() { bot.Unban(args[0]) })
+	case "!permit":
+		bot.requireMod(msg, func() { bot.handlePermitCommand(args) })
+	case "!points":
+		bot.requireMod(msg, func() { bot.handlePointsCommand(args) })
+	case "!givepoints":
+		bot.requireMod(msg, func() { bot.handleGivePointsCommand(args) })
+	case "!takepoints":
+		bot.requireMod(msg, func() { bot.handleTakePointsCommand(args) })
+	case "!balance":
+		bot.handleBalanceCommand(msg)
+	case "!top":
+		bot.handleTopCommand()
+	case "!op":
+		bot.requireMod(msg, func() { bot.handleOpCommand(args) })
+	case "!deop":
+		bot.requireMod(msg, func() { bot.handleDeopCommand(args) })
+	case "!bans":
+		bot.requireMod(msg, func() { bot.handleBansCommand(msg) })
+	case "!shutdown":
+		bot.requireMod(msg, func() {
+			bot.Message("Shutting down bot :(")
+			bot.Shutdown()
+		})
+	case "!kickall":
+		bot.requireMod(msg, func() { bot.KickAll() })
+	default:
+		if bot.loyalty != nil {
+			bot.loyalty.TryRedeem(msg.User, name)
 		}
-	} else {
-		bot.Message("You are not a mod " + user)
 	}
 }
 
+// requireMod runs fn if msg's sender is a moderator, otherwise it tells
+// them off; it centralizes the "mod-only command" gate that used to be
+// duplicated at the top of ParseCommand.
+func (bot *Bot) requireMod(msg Message, fn func()) {
+	if !bot.isModerator(msg) {
+		bot.Message("You are not a mod " + msg.DisplayName())
+		return
+	}
+	fn()
+}
+
+// handleTimeoutCommand parses "!timeout <user> <duration> [reason...]",
+// e.g. "!timeout someuser 10m posting links".
+func (bot *Bot) handleTimeoutCommand(args []string) {
+	if len(args) < 2 {
+		bot.Message("Usage: !timeout <user> <duration> [reason...]")
+		return
+	}
+	duration, err := time.ParseDuration(args[1])
+	if err != nil {
+		bot.Message("Invalid duration: " + args[1])
+		return
+	}
+	reason := strings.Join(args[2:], " ")
+	bot.Timeout(args[0], duration, reason)
+}
+
 /* main() driver instantiates a bot and gives its credentials
  * NOTE: the authentication in write "PASS" needs is refreshed about daily by Twitch;
  *		if the program isn't running it's most likely the oauth has expired
@@ -205,8 +331,19 @@ func (bot *Bot) ParseCommand(user string, theCommand string) {
 func main() {
 	channel := flag.String("channel", "ajs94", "The channel for the bot to go to")
 	nickname := flag.String("nickname", "testbot", "The bot's username")
-	automsg := flag.String("automessage", "This is an automessage message", "The automatic timed message")
-	autoMsgCount := flag.Int("autoMsgCount", 1, "The automessage's sleep time")
+	automessagesPath := flag.String("automessages", "automessages.yaml", "Path to the automessage scheduler's YAML config")
+	helixClientID := flag.String("helixClientID", "", "Twitch Helix client ID, needed for onlyOnLive automessages")
+	helixToken := flag.String("helixToken", "", "Twitch Helix app access token, needed for onlyOnLive automessages")
+	allowDomains := flag.String("allowDomains", "", "Comma-separated domains exempt from the link filter")
+	denyDomains := flag.String("denyDomains", "", "Comma-separated domains always timed out by the link filter")
+	loyaltyStorePath := flag.String("loyaltyStore", "loyalty.json", "Path to the loyalty points JSON store")
+	pointsPerTick := flag.Int64("pointsPerTick", 1, "Points granted to each active chatter per loyalty tick")
+	loyaltyTickInterval := flag.Duration("loyaltyTickInterval", 5*time.Minute, "How often loyalty points are granted")
+	loyaltyActivityWindow := flag.Duration("loyaltyActivityWindow", 10*time.Minute, "How recently a chatter must have been seen to count as active")
+	redeemablesPath := flag.String("redeemables", "redeemables.yaml", "Path to the loyalty Redeemables YAML config")
+	discordConfigPath := flag.String("discordConfig", "", "Path to the Discord bridge's JSON config; empty disables the bridge")
+	operatorsPath := flag.String("operators", "operators.json", "Path to the bot-side operators JSON store")
+	bannedPath := flag.String("banned", "banned_users.json", "Path to the bot's own banned-users JSON store")
 
 	bot := NewBot()
 	go bot.ConsoleInput()
@@ -215,11 +352,95 @@ func main() {
 	if (*channel) != "" {
 		bot.nickname = *nickname
 		bot.channel = "#" + *channel
-		bot.automsg = *automsg
-		bot.autoMsgCount = *autoMsgCount
+	}
+	bot.urlPolicy = NewURLPolicy(splitNonEmpty(*allowDomains), splitNonEmpty(*denyDomains))
+
+	helix := NewHelixClient(*helixClientID, *helixToken)
+	scheduler := NewAutoMessageScheduler(bot, helix)
+	if messages, err := LoadAutoMessages(*automessagesPath); err == nil {
+		for _, am := range messages {
+			if err := scheduler.Add(am); err != nil {
+				fmt.Printf("automessage: %v\n", err)
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		fmt.Printf("automessage: %v\n", err)
+	}
+
+	loyalty, err := NewLoyaltySystem(bot, *loyaltyStorePath, *pointsPerTick, *loyaltyTickInterval, *loyaltyActivityWindow)
+	if err != nil {
+		fmt.Printf("loyalty: %v\n", err)
+	} else {
+		bot.loyalty = loyalty
+		if redeemables, err := LoadRedeemables(*redeemablesPath); err == nil {
+			loyalty.Redeemables = redeemables
+		} else if !os.IsNotExist(err) {
+			fmt.Printf("loyalty: %v\n", err)
+		}
+		go loyalty.Run()
+		bot.OnShutdown(func() {
+			if err := loyalty.store.Flush(); err != nil {
+				fmt.Printf("loyalty: %v\n", err)
+			}
+		})
 	}
 
+	moderation, err := NewModerationStore(*operatorsPath, *bannedPath)
+	if err != nil {
+		fmt.Printf("moderation: %v\n", err)
+	} else {
+		bot.moderation = moderation
+	}
+
+	if *discordConfigPath != "" {
+		discordCfg, err := LoadDiscordConfig(*discordConfigPath)
+		if err != nil {
+			fmt.Printf("discord: %v\n", err)
+		} else {
+			bridge, err := NewDiscordBridge(bot, helix, *discordCfg)
+			if err != nil {
+				fmt.Printf("discord: %v\n", err)
+			} else {
+				go bridge.Run(bot.channel)
+			}
+		}
+	}
+
+	bot.OnPrivateMessage(func(bot *Bot, msg Message) {
+		scheduler.CountLine()
+		bot.userLastMsg.Touch(msg.User)
+		if bot.loyalty != nil {
+			bot.loyalty.Touch(msg.User)
+		}
+		fmt.Printf(msg.DisplayName() + " ")
+		fmt.Printf(msg.Text() + "\n")
+
+		if strings.HasPrefix(msg.Text(), "!") {
+			bot.ParseCommand(msg)
+		} else if bot.urlPolicy.ContainsDisallowedURL(msg.Text()) {
+			if bot.HasActivePermit(msg.User) {
+				return
+			}
+			bot.Timeout(msg.User, 10*time.Minute, "posting links without permission")
+		}
+	})
+
+	bot.OnJoin(func(bot *Bot, msg Message) {
+		bot.Message("PogChamp User Joined: " + msg.User)
+		if bot.loyalty != nil {
+			bot.loyalty.Touch(msg.User)
+		}
+	})
+
+	bot.OnPart(func(bot *Bot, msg Message) {
+		bot.Message("BibleThump User Left: " + msg.User)
+		if bot.loyalty != nil {
+			bot.loyalty.active.Remove(msg.User)
+		}
+	})
+
 	fmt.Printf("Giving info to server...\n")
+	bot.RequestCapabilities()
 	bot.conn.Write([]byte("PASS " + "oauth:fwnyam3yts63xngu801zmh6qhdmu9u" + "\r\n"))
 	bot.conn.Write([]byte("NICK " + "testbot" + "\r\n"))
 	bot.conn.Write([]byte("JOIN " + "#ajs94" + "\r\n"))
@@ -227,10 +448,10 @@ func main() {
 	fmt.Printf("Channel: " + bot.channel + "\n")
 
 	/* the keyword "go" indicates a goroutine; a concurrent function
-	 * 		in this case there are 3 concurrent funtions: Automessage, ConsoleInput, and main
+	 * 		in this case there are 3 concurrent funtions: the scheduler, ConsoleInput, and main
 	 */
 	defer bot.conn.Close()
-	go bot.Automessage()
+	go scheduler.Run()
 	input := bufio.NewReader(bot.conn)
 	tp := textproto.NewReader(input)
 	go bot.ConsoleInput()
@@ -241,49 +462,6 @@ func main() {
 		if err != nil {
 			break
 		}
-		// split the msg
-		msgParts := strings.Split(line, " ")
-
-		// if the msg contains PING you're required to
-		// respond with PONG else the bot gets kicked from twitch servers
-		if msgParts[0] == "PING" {
-			bot.conn.Write([]byte("PONG " + msgParts[1]))
-			continue
-		} else if strings.Contains(line, ".tmi.twitch.tv JOIN "+bot.channel) { // if a new user has joined
-			joindata := strings.Split(line, ".tmi.twitch.tv JOIN "+bot.channel)
-			userinfo := strings.Split(joindata[0], "@")
-			bot.Message("PogChamp User Joined: " + userinfo[1])
-		} else if strings.Contains(line, ".tmi.twitch.tv PART "+bot.channel) { // if a user has left
-			joindata := strings.Split(line, ".tmi.twitch.tv PART "+bot.channel)
-			userinfo := strings.Split(joindata[0], "@")
-			bot.Message("BibleThump User Left: " + userinfo[1])
-		} else if strings.HasPrefix(msgParts[3], ":!") { // check for commands
-			userdata := strings.Split(line, ".tmi.twitch.tv PRIVMSG "+bot.channel)
-			username := strings.Split(userdata[0], "@")
-			usermessage := strings.Replace(userdata[1], " :", "", 1)
-			fmt.Printf(username[1] + " ")
-			fmt.Printf(usermessage + "\n")
-			bot.ParseCommand(username[1], usermessage)
-		} else if isWebsite(msgParts[3]) {
-			userdata := strings.Split(line, ".tmi.twitch.tv PRIVMSG "+bot.channel)
-			username := strings.Split(userdata[0], "@")
-			bot.timeout(username[1])
-		}
-	}
-}
-
-/* isWebsite checks if a string has a website address
- * @param: theWebsite string, the message being checked
- * @return: true if theWebsite contains a url
- *			false if theWebsite does not
- */
-func isWebsite(theWebsite string) bool {
-	suffixes := []string{".com", ".net", ".org", ".tv", ".fm", ".gg"} // check online for more?
-	// self reminder _, is the blank identifier
-	for _, suffix := range suffixes {
-		if strings.Contains(theWebsite, suffix) {
-			return true
-		}
+		bot.handleLine(line)
 	}
-	return false
 }
@@ -0,0 +1,155 @@
+/* cron.go parses and matches a small cron dialect so automessages can be
+ * scheduled like "every 15 minutes between 18:00-22:59 on Sat/Sun"
+ * instead of a single fixed interval. It supports the standard 5-field
+ * form (minute hour dom month dow) as well as an optional leading
+ * seconds field (6-field form), since AutoMessageScheduler evaluates
+ * schedules once a second.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression. Each field is a bitset where bit
+// i set means "value i is allowed" for that field.
+type Schedule struct {
+	second, minute, hour, dom, month, dow uint64
+
+	// domStarred and dowStarred record whether the dom/dow fields were
+	// left as "*" (unrestricted), since that changes how the two
+	// combine in Matches.
+	domStarred, dowStarred bool
+}
+
+// ParseSchedule parses a 5-field (minute hour dom month dow) or 6-field
+// (second minute hour dom month dow) cron expression. Each field accepts
+// "*", a single value, a "lo-hi" range, a "/step", and comma-separated
+// lists of any of those, e.g. "*/15 18-22 * * 6,0".
+func ParseSchedule(expr string) (Schedule, error) {
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+		// already in second-first form
+	default:
+		return Schedule{}, fmt.Errorf("cron: expected 5 or 6 fields, got %d (%q)", len(fields), expr)
+	}
+
+	bounds := []struct{ min, max int }{
+		{0, 59}, // second
+		{0, 59}, // minute
+		{0, 23}, // hour
+		{1, 31}, // day of month
+		{1, 12}, // month
+		{0, 6},  // day of week, 0 = Sunday
+	}
+
+	bits := make([]uint64, 6)
+	for i, b := range bounds {
+		parsed, err := parseCronField(fields[i], b.min, b.max)
+		if err != nil {
+			return Schedule{}, err
+		}
+		bits[i] = parsed
+	}
+
+	return Schedule{
+		second:     bits[0],
+		minute:     bits[1],
+		hour:       bits[2],
+		dom:        bits[3],
+		month:      bits[4],
+		dow:        bits[5],
+		domStarred: fields[3] == "*",
+		dowStarred: fields[5] == "*",
+	}, nil
+}
+
+// parseCronField parses one comma-separated cron field into a bitset of
+// the values (within [min, max]) it selects.
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart := part
+		step := 1
+
+		if i := strings.IndexByte(part, '/'); i != -1 {
+			rangePart = part[:i]
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("cron: bad step in %q", part)
+			}
+			step = s
+		}
+
+		lo, hi := min, max
+		switch {
+		case rangePart == "*":
+			// lo, hi already span the full field range
+		case strings.Contains(rangePart, "-"):
+			a, b, found := strings.Cut(rangePart, "-")
+			if !found {
+				return 0, fmt.Errorf("cron: bad range in %q", part)
+			}
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(a)
+			hi, err2 = strconv.Atoi(b)
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("cron: bad range in %q", part)
+			}
+		default:
+			v, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("cron: bad value in %q", part)
+			}
+			lo, hi = v, v
+		}
+
+		for v := lo; v <= hi; v += step {
+			if v < min || v > max {
+				return 0, fmt.Errorf("cron: value %d out of range [%d,%d] in %q", v, min, max, part)
+			}
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Matches reports whether t falls within the schedule. Like standard
+// cron, day-of-month and day-of-week are special-cased: if both are
+// restricted (neither left as "*"), a match on either one is enough; if
+// only one is restricted, that one alone must match; if neither is
+// restricted, the day always matches.
+func (s Schedule) Matches(t time.Time) bool {
+	if s.second&(1<<uint(t.Second())) == 0 {
+		return false
+	}
+	if s.minute&(1<<uint(t.Minute())) == 0 {
+		return false
+	}
+	if s.hour&(1<<uint(t.Hour())) == 0 {
+		return false
+	}
+	if s.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case !s.domStarred && !s.dowStarred:
+		return domMatch || dowMatch
+	case !s.domStarred:
+		return domMatch
+	case !s.dowStarred:
+		return dowMatch
+	default:
+		return true
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestScheduleMatchesWeekendOnly covers the exact expression from the
+// automessage request ("*/15 18-22 * * 6,0": every 15 minutes, 18:00-22:59,
+// Saturday and Sunday only) and checks it does NOT fire on a weekday even
+// though day-of-month is left as "*".
+func TestScheduleMatchesWeekendOnly(t *testing.T) {
+	sched, err := ParseSchedule("*/15 18-22 * * 6,0")
+	if err != nil {
+		t.Fatalf("ParseSchedule: %v", err)
+	}
+
+	monday := time.Date(2026, time.July, 27, 19, 0, 0, 0, time.UTC)
+	if sched.Matches(monday) {
+		t.Errorf("Matches(%v) = true, want false (Monday is not in 6,0)", monday)
+	}
+
+	saturday := time.Date(2026, time.July, 25, 19, 0, 0, 0, time.UTC)
+	if !sched.Matches(saturday) {
+		t.Errorf("Matches(%v) = false, want true (Saturday at 19:00 is in schedule)", saturday)
+	}
+}
+
+// TestScheduleMatchesDomAndDowRestricted covers the standard cron OR
+// semantics when both day-of-month and day-of-week are restricted.
+func TestScheduleMatchesDomAndDowRestricted(t *testing.T) {
+	sched, err := ParseSchedule("0 0 1 * 1")
+	if err != nil {
+		t.Fatalf("ParseSchedule: %v", err)
+	}
+
+	firstOfMonth := time.Date(2026, time.July, 1, 0, 0, 0, 0, time.UTC) // a Wednesday
+	if !sched.Matches(firstOfMonth) {
+		t.Errorf("Matches(%v) = false, want true (matches via dom)", firstOfMonth)
+	}
+
+	monday := time.Date(2026, time.July, 6, 0, 0, 0, 0, time.UTC)
+	if !sched.Matches(monday) {
+		t.Errorf("Matches(%v) = false, want true (matches via dow)", monday)
+	}
+
+	tuesday := time.Date(2026, time.July, 7, 0, 0, 0, 0, time.UTC)
+	if sched.Matches(tuesday) {
+		t.Errorf("Matches(%v) = true, want false (matches neither dom nor dow)", tuesday)
+	}
+}
@@ -0,0 +1,167 @@
+/* discord.go bridges the Twitch bot to a Discord server: chat relay in
+ * both directions, plus a Helix poller that posts a rich embed when the
+ * Twitch channel goes live. All Discord IDs (guild, relay channel,
+ * live-notification channel, mention role) come from a JSON config
+ * file rather than flags, since there are enough of them to be
+ * unwieldy on the command line.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// DiscordConfig holds everything DiscordBridge needs to know about the
+// target Discord server.
+type DiscordConfig struct {
+	BotToken       string `json:"bot_token"`
+	GuildID        string `json:"guild_id"`
+	RelayChannelID string `json:"relay_channel_id"`
+	LiveChannelID  string `json:"live_channel_id"`
+	MentionRoleID  string `json:"mention_role_id"`
+}
+
+// LoadDiscordConfig reads a DiscordConfig from a JSON file at path.
+func LoadDiscordConfig(path string) (*DiscordConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg DiscordConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// offlineGrace is how long the Twitch channel must stay offline before
+// DiscordBridge will treat a subsequent live event as a new stream
+// worth announcing, rather than a brief disconnect/reconnect flap.
+const offlineGrace = 10 * time.Minute
+
+// DiscordBridge relays chat between Twitch and Discord and posts a live
+// notification embed when the configured Twitch channel goes live.
+type DiscordBridge struct {
+	cfg     DiscordConfig
+	session *discordgo.Session
+	bot     *Bot
+	helix   *HelixClient
+
+	lastStreamID string
+	wentOffline  time.Time
+}
+
+// NewDiscordBridge opens a Discord session for cfg.BotToken, wires chat
+// relaying to/from bot, and registers a shutdown hook to close the
+// session on "!quit". Call Run separately to start the live poller.
+func NewDiscordBridge(bot *Bot, helix *HelixClient, cfg DiscordConfig) (*DiscordBridge, error) {
+	session, err := discordgo.New("Bot " + cfg.BotToken)
+	if err != nil {
+		return nil, err
+	}
+
+	bridge := &DiscordBridge{cfg: cfg, session: session, bot: bot, helix: helix}
+
+	session.AddHandler(func(s *discordgo.Session, m *discordgo.MessageCreate) {
+		if m.Author.Bot || m.ChannelID != cfg.RelayChannelID {
+			return
+		}
+		bot.Message(fmt.Sprintf("[discord] %s: %s", m.Author.Username, m.Content))
+	})
+
+	if err := session.Open(); err != nil {
+		return nil, err
+	}
+
+	bot.OnPrivateMessage(func(bot *Bot, msg Message) {
+		bridge.relayToDiscord(msg)
+	})
+	bot.OnShutdown(func() {
+		session.Close()
+	})
+
+	return bridge, nil
+}
+
+// relayToDiscord forwards a Twitch chat message to the configured relay
+// channel, prefixed so it's clear where it came from.
+func (d *DiscordBridge) relayToDiscord(msg Message) {
+	if d.cfg.RelayChannelID == "" {
+		return
+	}
+	d.session.ChannelMessageSend(d.cfg.RelayChannelID, fmt.Sprintf("[twitch] %s: %s", msg.DisplayName(), msg.Text()))
+}
+
+// Run polls Helix for twitchChannel's live status every 60s until the
+// process exits; call it in a goroutine.
+func (d *DiscordBridge) Run(twitchChannel string) {
+	login := strings.TrimPrefix(twitchChannel, "#")
+	ticker := time.NewTicker(60 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		d.checkLive(login)
+	}
+}
+
+func (d *DiscordBridge) checkLive(login string) {
+	info, err := d.helix.Stream(login)
+	if err != nil {
+		fmt.Printf("discord: %v\n", err)
+		return
+	}
+
+	if !info.Live {
+		if d.lastStreamID != "" && d.wentOffline.IsZero() {
+			d.wentOffline = time.Now()
+		}
+		return
+	}
+
+	if info.ID == d.lastStreamID {
+		return
+	}
+	if !d.wentOffline.IsZero() && time.Since(d.wentOffline) < offlineGrace {
+		// seen this streamer go briefly offline and come back: treat it as
+		// a continuation of the same session rather than a new one
+		d.lastStreamID = info.ID
+		return
+	}
+
+	d.lastStreamID = info.ID
+	d.wentOffline = time.Time{}
+	d.announceLive(login, info)
+}
+
+// announceLive posts a rich embed to the configured live-notification
+// channel, optionally @-mentioning MentionRoleID.
+func (d *DiscordBridge) announceLive(login string, info StreamInfo) {
+	if d.cfg.LiveChannelID == "" {
+		return
+	}
+
+	content := ""
+	if d.cfg.MentionRoleID != "" {
+		content = "<@&" + d.cfg.MentionRoleID + ">"
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       info.Title,
+		URL:         "https://twitch.tv/" + login,
+		Description: info.Game,
+		Thumbnail: &discordgo.MessageEmbedThumbnail{
+			URL: fmt.Sprintf("https://static-cdn.jtvnw.net/previews-ttv/live_user_%s-440x248.jpg", login),
+		},
+	}
+
+	d.session.ChannelMessageSendComplex(d.cfg.LiveChannelID, &discordgo.MessageSend{
+		Content: content,
+		Embed:   embed,
+	})
+}
@@ -0,0 +1,111 @@
+/* helix.go is a small client for the one Twitch Helix endpoint the bot
+ * needs so far: checking whether a channel is currently live. Responses
+ * are cached briefly since both the automessage scheduler and (later)
+ * the Discord live-notification poller call it frequently.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// streamCacheTTL bounds how long a Stream() result is reused before
+// HelixClient hits the API again for the same channel.
+const streamCacheTTL = 30 * time.Second
+
+// StreamInfo describes a channel's current live status.
+type StreamInfo struct {
+	Live    bool
+	ID      string
+	Title   string
+	Game    string
+	Viewers int
+}
+
+type cachedStream struct {
+	info    StreamInfo
+	fetched time.Time
+}
+
+// HelixClient authenticates with a Twitch app access token and answers
+// live-status queries, caching each channel's result for streamCacheTTL.
+type HelixClient struct {
+	ClientID    string
+	AccessToken string
+
+	mu    sync.Mutex
+	cache map[string]cachedStream
+}
+
+// NewHelixClient builds a client using clientID/accessToken for the
+// "Client-Id" and "Authorization: Bearer" headers Helix requires.
+func NewHelixClient(clientID, accessToken string) *HelixClient {
+	return &HelixClient{
+		ClientID:    clientID,
+		AccessToken: accessToken,
+		cache:       make(map[string]cachedStream),
+	}
+}
+
+// Stream returns login's current live status via GET /streams?user_login=,
+// serving a cached result when one is younger than streamCacheTTL.
+func (h *HelixClient) Stream(login string) (StreamInfo, error) {
+	h.mu.Lock()
+	if cached, ok := h.cache[login]; ok && time.Since(cached.fetched) < streamCacheTTL {
+		h.mu.Unlock()
+		return cached.info, nil
+	}
+	h.mu.Unlock()
+
+	info, err := h.fetchStream(login)
+	if err != nil {
+		return StreamInfo{}, err
+	}
+
+	h.mu.Lock()
+	h.cache[login] = cachedStream{info: info, fetched: time.Now()}
+	h.mu.Unlock()
+	return info, nil
+}
+
+func (h *HelixClient) fetchStream(login string) (StreamInfo, error) {
+	req, err := http.NewRequest(http.MethodGet, "https://api.twitch.tv/helix/streams?user_login="+login, nil)
+	if err != nil {
+		return StreamInfo{}, err
+	}
+	req.Header.Set("Client-Id", h.ClientID)
+	req.Header.Set("Authorization", "Bearer "+h.AccessToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return StreamInfo{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return StreamInfo{}, fmt.Errorf("helix: unexpected status %s", resp.Status)
+	}
+
+	var body struct {
+		Data []struct {
+			ID          string `json:"id"`
+			Title       string `json:"title"`
+			GameName    string `json:"game_name"`
+			ViewerCount int    `json:"viewer_count"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return StreamInfo{}, err
+	}
+	if len(body.Data) == 0 {
+		return StreamInfo{}, nil // offline
+	}
+
+	d := body.Data[0]
+	return StreamInfo{Live: true, ID: d.ID, Title: d.Title, Game: d.GameName, Viewers: d.ViewerCount}, nil
+}
@@ -0,0 +1,42 @@
+/* last_msg_tracker.go tracks the last time each chatter posted, so
+ * KickAll() can time out everyone the bot has seen without racing the
+ * IRC read loop that records each new message.
+ */
+
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// LastMsgTracker records the last-message time for each chatter.
+type LastMsgTracker struct {
+	mu      sync.Mutex
+	lastMsg map[string]int64
+}
+
+// NewLastMsgTracker builds an empty tracker.
+func NewLastMsgTracker() *LastMsgTracker {
+	return &LastMsgTracker{lastMsg: make(map[string]int64)}
+}
+
+// Touch records username as having posted right now.
+func (l *LastMsgTracker) Touch(username string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastMsg[strings.ToLower(username)] = time.Now().Unix()
+}
+
+// Usernames returns a snapshot of every chatter currently tracked.
+func (l *LastMsgTracker) Usernames() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	usernames := make([]string, 0, len(l.lastMsg))
+	for username := range l.lastMsg {
+		usernames = append(usernames, username)
+	}
+	return usernames
+}
@@ -0,0 +1,192 @@
+/* loyalty.go implements a StreamElements/Strimertul-style points system:
+ * chatters earn points for being active in chat, mods can adjust
+ * balances directly, and streamers can define custom "redeemables" that
+ * spend points for a templated chat response.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Redeemable is a custom "!commandname" that spends Cost points and
+// prints Response, gated by a per-user Cooldown.
+type Redeemable struct {
+	Cost     int64
+	Response string
+	Cooldown time.Duration
+
+	mu           sync.Mutex
+	lastRedeemed map[string]time.Time
+}
+
+// LoyaltySystem ties together a LoyaltyStore (balances) and an
+// ActiveUserTracker (who to pay for being around), paying
+// PointsPerTick to everyone active within ActivityWindow every
+// TickInterval.
+type LoyaltySystem struct {
+	bot            *Bot
+	store          *LoyaltyStore
+	active         *ActiveUserTracker
+	PointsPerTick  int64
+	TickInterval   time.Duration
+	ActivityWindow time.Duration
+	Redeemables    map[string]*Redeemable
+}
+
+// NewLoyaltySystem builds a LoyaltySystem persisting balances to
+// storePath.
+func NewLoyaltySystem(bot *Bot, storePath string, pointsPerTick int64, tickInterval, activityWindow time.Duration) (*LoyaltySystem, error) {
+	store, err := NewLoyaltyStore(storePath)
+	if err != nil {
+		return nil, err
+	}
+	return &LoyaltySystem{
+		bot:            bot,
+		store:          store,
+		active:         NewActiveUserTracker(),
+		PointsPerTick:  pointsPerTick,
+		TickInterval:   tickInterval,
+		ActivityWindow: activityWindow,
+		Redeemables:    make(map[string]*Redeemable),
+	}, nil
+}
+
+// Touch marks username as active right now, so the next tick's payout
+// includes them.
+func (l *LoyaltySystem) Touch(username string) {
+	l.active.Touch(username)
+}
+
+// flushInterval is how often Run debounce-flushes the store to disk.
+const flushInterval = 10 * time.Second
+
+// Run pays out PointsPerTick to everyone active within ActivityWindow
+// every TickInterval, and periodically flushes the store to disk. It
+// blocks, so call it in a goroutine.
+func (l *LoyaltySystem) Run() {
+	payTicker := time.NewTicker(l.TickInterval)
+	flushTicker := time.NewTicker(flushInterval)
+	defer payTicker.Stop()
+	defer flushTicker.Stop()
+
+	for {
+		select {
+		case <-payTicker.C:
+			for _, username := range l.active.Active(l.ActivityWindow) {
+				l.store.Add(username, l.PointsPerTick)
+			}
+		case <-flushTicker.C:
+			if err := l.store.Flush(); err != nil {
+				fmt.Printf("loyalty: %v\n", err)
+			}
+		}
+	}
+}
+
+// TryRedeem spends Cost points from username against the redeemable
+// named commandName (without its leading "!"), printing its templated
+// Response on success. It reports whether commandName matched a
+// configured redeemable at all, regardless of whether the spend
+// succeeded, so callers can tell "not a redemption" from "redeemed".
+func (l *LoyaltySystem) TryRedeem(username, commandName string) bool {
+	redeemable, ok := l.Redeemables[strings.ToLower(commandName)]
+	if !ok {
+		return false
+	}
+
+	redeemable.mu.Lock()
+	if until, seen := redeemable.lastRedeemed[username]; seen && time.Now().Before(until) {
+		redeemable.mu.Unlock()
+		l.bot.Message(fmt.Sprintf("%s: that redemption is on cooldown", username))
+		return true
+	}
+	redeemable.mu.Unlock()
+
+	if _, err := l.store.Take(username, redeemable.Cost); err != nil {
+		l.bot.Message(fmt.Sprintf("%s: you don't have enough points", username))
+		return true
+	}
+
+	redeemable.mu.Lock()
+	if redeemable.lastRedeemed == nil {
+		redeemable.lastRedeemed = make(map[string]time.Time)
+	}
+	redeemable.lastRedeemed[username] = time.Now().Add(redeemable.Cooldown)
+	redeemable.mu.Unlock()
+
+	l.bot.Message(redeemable.Response)
+	return true
+}
+
+// handlePointsCommand implements the mod-only "!points <user>".
+func (bot *Bot) handlePointsCommand(args []string) {
+	if bot.loyalty == nil || len(args) < 1 {
+		return
+	}
+	bot.Message(fmt.Sprintf("%s has %d points", args[0], bot.loyalty.store.Balance(args[0])))
+}
+
+// handleGivePointsCommand implements the mod-only "!givepoints <user> <n>".
+func (bot *Bot) handleGivePointsCommand(args []string) {
+	if bot.loyalty == nil || len(args) < 2 {
+		bot.Message("Usage: !givepoints <user> <n>")
+		return
+	}
+	n, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		bot.Message("Invalid amount: " + args[1])
+		return
+	}
+	balance := bot.loyalty.store.Add(args[0], n)
+	bot.Message(fmt.Sprintf("Gave %d points to %s (new balance: %d)", n, args[0], balance))
+}
+
+// handleTakePointsCommand implements the mod-only "!takepoints <user> <n>".
+func (bot *Bot) handleTakePointsCommand(args []string) {
+	if bot.loyalty == nil || len(args) < 2 {
+		bot.Message("Usage: !takepoints <user> <n>")
+		return
+	}
+	n, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		bot.Message("Invalid amount: " + args[1])
+		return
+	}
+	balance, err := bot.loyalty.store.Take(args[0], n)
+	if err != nil {
+		bot.Message(fmt.Sprintf("%s only has %d points", args[0], bot.loyalty.store.Balance(args[0])))
+		return
+	}
+	bot.Message(fmt.Sprintf("Took %d points from %s (new balance: %d)", n, args[0], balance))
+}
+
+// handleBalanceCommand implements the user-facing "!balance".
+func (bot *Bot) handleBalanceCommand(msg Message) {
+	if bot.loyalty == nil {
+		return
+	}
+	bot.Message(fmt.Sprintf("%s: you have %d points", msg.DisplayName(), bot.loyalty.store.Balance(msg.User)))
+}
+
+// handleTopCommand implements the user-facing "!top".
+func (bot *Bot) handleTopCommand() {
+	if bot.loyalty == nil {
+		return
+	}
+	top := bot.loyalty.store.Top(5)
+	if len(top) == 0 {
+		bot.Message("No points have been earned yet")
+		return
+	}
+	parts := make([]string, len(top))
+	for i, entry := range top {
+		parts[i] = fmt.Sprintf("%s: %d", entry.Username, entry.Balance)
+	}
+	bot.Message("Top balances - " + strings.Join(parts, ", "))
+}
@@ -0,0 +1,85 @@
+/* loyalty_config.go hand-rolls a small YAML reader for the Redeemables
+ * config: a top-level mapping of "!commandname" -> {cost, response,
+ * cooldown}. Same rationale as automessage_config.go: this is all the
+ * format needs, so a full YAML library isn't worth the dependency.
+ */
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoadRedeemables reads a Redeemables config from path, keyed by
+// command name including its leading "!", e.g.:
+//
+//	!hydrate:
+//	  cost: 50
+//	  response: "Drink some water!"
+//	  cooldown: 5m
+func LoadRedeemables(path string) (map[string]*Redeemable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	redeemables := make(map[string]*Redeemable)
+	var name string
+	var current *Redeemable
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		raw := scanner.Text()
+		trimmed := strings.TrimSpace(raw)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		// an unindented line ending in ":" starts a new "!commandname:" block
+		if !strings.HasPrefix(raw, " ") && !strings.HasPrefix(raw, "\t") && strings.HasSuffix(trimmed, ":") {
+			name = strings.TrimSuffix(trimmed, ":")
+			current = &Redeemable{}
+			redeemables[strings.ToLower(name)] = current
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+
+		switch key {
+		case "cost":
+			cost, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: redeemable %s: cost: %w", path, name, err)
+			}
+			current.Cost = cost
+		case "response":
+			current.Response = value
+		case "cooldown":
+			cooldown, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: redeemable %s: cooldown: %w", path, name, err)
+			}
+			current.Cooldown = cooldown
+		default:
+			return nil, fmt.Errorf("%s: redeemable %s: unknown field %q", path, name, key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return redeemables, nil
+}
@@ -0,0 +1,123 @@
+/* loyalty_store.go persists loyalty point balances as JSON on disk,
+ * keyed by lowercased username. A BoltDB-backed store would avoid
+ * rewriting the whole file on every flush, but the balance counts
+ * involved are small enough that a plain JSON file stays simple and
+ * human-inspectable, and doesn't pull in another dependency on top of
+ * the Discord bridge's discordgo.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LoyaltyEntry is one row of a Top() result.
+type LoyaltyEntry struct {
+	Username string
+	Balance  int64
+}
+
+// LoyaltyStore holds loyalty point balances in memory and flushes them
+// to disk on a debounce rather than on every write.
+type LoyaltyStore struct {
+	path string
+
+	mu       sync.Mutex
+	balances map[string]int64
+	dirty    bool
+}
+
+// NewLoyaltyStore loads balances from path, starting empty if the file
+// doesn't exist yet.
+func NewLoyaltyStore(path string) (*LoyaltyStore, error) {
+	store := &LoyaltyStore{path: path, balances: make(map[string]int64)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store.balances); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Balance returns username's current balance.
+func (s *LoyaltyStore) Balance(username string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.balances[strings.ToLower(username)]
+}
+
+// Add credits amount (which may be negative) to username's balance,
+// returning the new balance.
+func (s *LoyaltyStore) Add(username string, amount int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := strings.ToLower(username)
+	s.balances[key] += amount
+	s.dirty = true
+	return s.balances[key]
+}
+
+// Take debits amount from username's balance, failing without changing
+// it if that would make the balance negative.
+func (s *LoyaltyStore) Take(username string, amount int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := strings.ToLower(username)
+	if s.balances[key] < amount {
+		return s.balances[key], errors.New("insufficient balance")
+	}
+	s.balances[key] -= amount
+	s.dirty = true
+	return s.balances[key], nil
+}
+
+// Top returns the n highest balances, highest first.
+func (s *LoyaltyStore) Top(n int) []LoyaltyEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]LoyaltyEntry, 0, len(s.balances))
+	for username, balance := range s.balances {
+		entries = append(entries, LoyaltyEntry{Username: username, Balance: balance})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Balance > entries[j].Balance })
+
+	if n < len(entries) {
+		entries = entries[:n]
+	}
+	return entries
+}
+
+// Flush writes balances to disk if they've changed since the last
+// Flush, atomically (write-temp + rename).
+func (s *LoyaltyStore) Flush() error {
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	data, err := json.MarshalIndent(s.balances, "", "  ")
+	s.dirty = false
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
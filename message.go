@@ -0,0 +1,226 @@
+/* message.go parses raw lines from the Twitch IRC connection into a
+ * structured Message (including IRCv3 tags) and dispatches them to
+ * whatever handlers the rest of the bot has registered. This replaces
+ * the old "split the line on spaces and index into it" approach that
+ * broke as soon as Twitch sent a tagged message (whispers, raids,
+ * CLEARCHAT, USERSTATE, ...).
+ */
+
+package main
+
+import (
+	"strings"
+)
+
+/* Message is a single parsed IRC line.
+ *		Tags holds the IRCv3 @key=value pairs, if any (e.g. "mod", "badges",
+ *			"user-id", "room-id", "display-name").
+ *		Prefix is the raw ":nick!user@host" (or server name) before Command.
+ *		User is the nick portion of Prefix, already stripped of !user@host.
+ *		Command is the IRC/Twitch verb (PRIVMSG, JOIN, PART, CLEARCHAT, ...).
+ *		Params are the arguments following Command, with the trailing
+ *			(":"-prefixed) parameter as the last element.
+ */
+type Message struct {
+	Tags    map[string]string
+	Prefix  string
+	User    string
+	Command string
+	Params  []string
+	Raw     string
+}
+
+// PrivateMessageHandler is called for every PRIVMSG (a chat message).
+type PrivateMessageHandler func(bot *Bot, msg Message)
+
+// JoinHandler is called when a user joins bot.channel.
+type JoinHandler func(bot *Bot, msg Message)
+
+// PartHandler is called when a user leaves bot.channel.
+type PartHandler func(bot *Bot, msg Message)
+
+// ClearChatHandler is called on CLEARCHAT (a timeout, ban, or /clear).
+type ClearChatHandler func(bot *Bot, msg Message)
+
+// UserNoticeHandler is called on USERNOTICE (raids, subs, resubs, ...).
+type UserNoticeHandler func(bot *Bot, msg Message)
+
+// WhisperHandler is called when the bot receives a whisper.
+type WhisperHandler func(bot *Bot, msg Message)
+
+// OnPrivateMessage registers a handler to run for every PRIVMSG.
+func (bot *Bot) OnPrivateMessage(handler PrivateMessageHandler) {
+	bot.onPrivateMessage = append(bot.onPrivateMessage, handler)
+}
+
+// OnJoin registers a handler to run whenever a user joins the channel.
+func (bot *Bot) OnJoin(handler JoinHandler) {
+	bot.onJoin = append(bot.onJoin, handler)
+}
+
+// OnPart registers a handler to run whenever a user leaves the channel.
+func (bot *Bot) OnPart(handler PartHandler) {
+	bot.onPart = append(bot.onPart, handler)
+}
+
+// OnClearChat registers a handler to run on CLEARCHAT.
+func (bot *Bot) OnClearChat(handler ClearChatHandler) {
+	bot.onClearChat = append(bot.onClearChat, handler)
+}
+
+// OnUserNotice registers a handler to run on USERNOTICE (raids, subs).
+func (bot *Bot) OnUserNotice(handler UserNoticeHandler) {
+	bot.onUserNotice = append(bot.onUserNotice, handler)
+}
+
+// OnWhisper registers a handler to run whenever the bot is whispered.
+func (bot *Bot) OnWhisper(handler WhisperHandler) {
+	bot.onWhisper = append(bot.onWhisper, handler)
+}
+
+/* ParseMessage turns one raw IRC line into a Message. It understands the
+ * optional leading "@tag=value;..." block introduced by the IRCv3 tags
+ * capability, which arrives before the ":prefix" on every tagged line.
+ */
+func ParseMessage(line string) Message {
+	msg := Message{Tags: map[string]string{}, Raw: line}
+
+	if strings.HasPrefix(line, "@") {
+		split := strings.SplitN(line, " ", 2)
+		msg.Tags = parseTags(split[0][1:])
+		if len(split) < 2 {
+			return msg
+		}
+		line = split[1]
+	}
+
+	if strings.HasPrefix(line, ":") {
+		split := strings.SplitN(line, " ", 2)
+		msg.Prefix = split[0][1:]
+		if i := strings.IndexByte(msg.Prefix, '!'); i != -1 {
+			msg.User = msg.Prefix[:i]
+		} else {
+			msg.User = msg.Prefix
+		}
+		if len(split) < 2 {
+			return msg
+		}
+		line = split[1]
+	}
+
+	if i := strings.Index(line, " :"); i != -1 {
+		trailing := line[i+2:]
+		fields := strings.Fields(line[:i])
+		if len(fields) == 0 {
+			return msg
+		}
+		msg.Command = fields[0]
+		msg.Params = append(fields[1:], trailing)
+	} else {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return msg
+		}
+		msg.Command = fields[0]
+		msg.Params = fields[1:]
+	}
+
+	return msg
+}
+
+// parseTags splits a raw "key=value;key=value" tag block into a map,
+// unescaping the handful of characters Twitch escapes in tag values.
+func parseTags(raw string) map[string]string {
+	tags := make(map[string]string)
+	for _, pair := range strings.Split(raw, ";") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			continue
+		}
+		value := strings.NewReplacer(`\s`, " ", `\:`, ";", `\\`, `\`).Replace(kv[1])
+		tags[kv[0]] = value
+	}
+	return tags
+}
+
+// Channel returns the channel (room) the message belongs to, i.e. the
+// first param of PRIVMSG/JOIN/PART/CLEARCHAT/USERNOTICE lines.
+func (msg Message) Channel() string {
+	if len(msg.Params) == 0 {
+		return ""
+	}
+	return msg.Params[0]
+}
+
+// Text returns the trailing parameter, i.e. the chat message text.
+func (msg Message) Text() string {
+	if len(msg.Params) == 0 {
+		return ""
+	}
+	return msg.Params[len(msg.Params)-1]
+}
+
+// IsMod reports whether the tags on this message mark its sender as a
+// channel moderator or the broadcaster.
+func (msg Message) IsMod() bool {
+	if msg.Tags["mod"] == "1" {
+		return true
+	}
+	badges := msg.Tags["badges"]
+	return strings.Contains(badges, "broadcaster/1") || strings.Contains(badges, "moderator/1")
+}
+
+// UserID returns the twitch-assigned user-id tag of the sender, if present.
+func (msg Message) UserID() string {
+	return msg.Tags["user-id"]
+}
+
+// RoomID returns the twitch-assigned room-id tag of the channel, if present.
+func (msg Message) RoomID() string {
+	return msg.Tags["room-id"]
+}
+
+// DisplayName returns the sender's display-name tag, falling back to User.
+func (msg Message) DisplayName() string {
+	if name := msg.Tags["display-name"]; name != "" {
+		return name
+	}
+	return msg.User
+}
+
+/* handleLine parses one raw line and dispatches it to the matching
+ * handlers. PING is handled here directly since it's a connection-level
+ * concern, not something bot logic should need to opt into.
+ */
+func (bot *Bot) handleLine(line string) {
+	msg := ParseMessage(line)
+
+	switch msg.Command {
+	case "PING":
+		bot.conn.Write([]byte("PONG :" + msg.Text() + "\r\n"))
+	case "PRIVMSG":
+		for _, handler := range bot.onPrivateMessage {
+			handler(bot, msg)
+		}
+	case "WHISPER":
+		for _, handler := range bot.onWhisper {
+			handler(bot, msg)
+		}
+	case "JOIN":
+		for _, handler := range bot.onJoin {
+			handler(bot, msg)
+		}
+	case "PART":
+		for _, handler := range bot.onPart {
+			handler(bot, msg)
+		}
+	case "CLEARCHAT":
+		for _, handler := range bot.onClearChat {
+			handler(bot, msg)
+		}
+	case "USERNOTICE":
+		for _, handler := range bot.onUserNotice {
+			handler(bot, msg)
+		}
+	}
+}
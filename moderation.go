@@ -0,0 +1,179 @@
+/* moderation.go persists bot-side state across restarts: the operators
+ * the streamer has delegated bot control to via "!op" (independent of
+ * Twitch channel mod status), and the bot's own record of who it has
+ * banned. Both are JSON files, written atomically (write-temp +
+ * rename) on every change so a crash mid-write can't corrupt them.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ModerationStore persists bot-side operators and the bot's ban record,
+// both keyed by lowercased username.
+type ModerationStore struct {
+	operatorsPath string
+	bannedPath    string
+
+	mu        sync.Mutex
+	operators map[string]bool
+	banned    map[string]string // username -> reason
+}
+
+// NewModerationStore loads operators/bans from operatorsPath/bannedPath,
+// starting empty for either file that doesn't exist yet.
+func NewModerationStore(operatorsPath, bannedPath string) (*ModerationStore, error) {
+	store := &ModerationStore{
+		operatorsPath: operatorsPath,
+		bannedPath:    bannedPath,
+		operators:     make(map[string]bool),
+		banned:        make(map[string]string),
+	}
+	if err := loadJSONFile(operatorsPath, &store.operators); err != nil {
+		return nil, err
+	}
+	if err := loadJSONFile(bannedPath, &store.banned); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func loadJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func writeJSONFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// IsOperator reports whether username has been granted bot-side
+// operator status via "!op".
+func (m *ModerationStore) IsOperator(username string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.operators[strings.ToLower(username)]
+}
+
+// Op grants username bot-side operator status and persists it.
+func (m *ModerationStore) Op(username string) error {
+	m.mu.Lock()
+	m.operators[strings.ToLower(username)] = true
+	data, err := json.MarshalIndent(m.operators, "", "  ")
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeJSONFileAtomic(m.operatorsPath, data)
+}
+
+// Deop revokes username's bot-side operator status and persists it.
+func (m *ModerationStore) Deop(username string) error {
+	m.mu.Lock()
+	delete(m.operators, strings.ToLower(username))
+	data, err := json.MarshalIndent(m.operators, "", "  ")
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeJSONFileAtomic(m.operatorsPath, data)
+}
+
+// RecordBan records that username has been banned for reason and
+// persists it.
+func (m *ModerationStore) RecordBan(username, reason string) error {
+	m.mu.Lock()
+	m.banned[strings.ToLower(username)] = reason
+	data, err := json.MarshalIndent(m.banned, "", "  ")
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeJSONFileAtomic(m.bannedPath, data)
+}
+
+// RecordUnban removes username from the ban record and persists it.
+func (m *ModerationStore) RecordUnban(username string) error {
+	m.mu.Lock()
+	delete(m.banned, strings.ToLower(username))
+	data, err := json.MarshalIndent(m.banned, "", "  ")
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeJSONFileAtomic(m.bannedPath, data)
+}
+
+// BanList returns "username: reason" for every currently-recorded ban,
+// sorted by username.
+func (m *ModerationStore) BanList() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	usernames := make([]string, 0, len(m.banned))
+	for username := range m.banned {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+
+	lines := make([]string, len(usernames))
+	for i, username := range usernames {
+		lines[i] = username + ": " + m.banned[username]
+	}
+	return lines
+}
+
+// handleOpCommand implements the mod-only "!op <user>".
+func (bot *Bot) handleOpCommand(args []string) {
+	if bot.moderation == nil || len(args) < 1 {
+		return
+	}
+	if err := bot.moderation.Op(args[0]); err != nil {
+		fmt.Printf("moderation: %v\n", err)
+		return
+	}
+	bot.Message(args[0] + " is now a bot operator")
+}
+
+// handleDeopCommand implements the mod-only "!deop <user>".
+func (bot *Bot) handleDeopCommand(args []string) {
+	if bot.moderation == nil || len(args) < 1 {
+		return
+	}
+	if err := bot.moderation.Deop(args[0]); err != nil {
+		fmt.Printf("moderation: %v\n", err)
+		return
+	}
+	bot.Message(args[0] + " is no longer a bot operator")
+}
+
+// handleBansCommand implements the mod-only "!bans": it whispers the
+// current banlist to whoever asked rather than posting it in chat.
+func (bot *Bot) handleBansCommand(msg Message) {
+	if bot.moderation == nil {
+		return
+	}
+	bans := bot.moderation.BanList()
+	if len(bans) == 0 {
+		bot.WhisperTo(msg.User, "No bans recorded")
+		return
+	}
+	bot.WhisperTo(msg.User, strings.Join(bans, "; "))
+}
@@ -0,0 +1,81 @@
+/* permit.go implements "!permit <user> [seconds]": a mod-granted grace
+ * period during which URLPolicy violations are let through instead of
+ * timed out, tracked per-user in a PermitStore.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultPermitGrace is used when "!permit <user>" is called with no
+// explicit number of seconds.
+const defaultPermitGrace = 30 * time.Second
+
+// PermitStore tracks, per user, the time their current link permit
+// expires. It's read from the IRC read loop and written from the
+// console-command goroutine, so access is guarded by mu.
+type PermitStore struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+// NewPermitStore builds an empty store.
+func NewPermitStore() *PermitStore {
+	return &PermitStore{until: make(map[string]time.Time)}
+}
+
+// HasActivePermit reports whether username currently holds an unexpired
+// permit granted via Grant().
+func (p *PermitStore) HasActivePermit(username string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	until, ok := p.until[strings.ToLower(username)]
+	return ok && time.Now().Before(until)
+}
+
+// Grant gives username a grace window (defaultPermitGrace if grace is
+// zero) during which a posted link won't be timed out.
+func (p *PermitStore) Grant(username string, grace time.Duration) time.Duration {
+	if grace <= 0 {
+		grace = defaultPermitGrace
+	}
+	p.mu.Lock()
+	p.until[strings.ToLower(username)] = time.Now().Add(grace)
+	p.mu.Unlock()
+	return grace
+}
+
+// HasActivePermit reports whether username currently holds an unexpired
+// permit granted via Permit().
+func (bot *Bot) HasActivePermit(username string) bool {
+	return bot.permits.HasActivePermit(username)
+}
+
+// Permit grants username a grace window (defaultPermitGrace if grace is
+// zero) during which a posted link won't be timed out, and announces it.
+func (bot *Bot) Permit(username string, grace time.Duration) {
+	grace = bot.permits.Grant(username, grace)
+	bot.Message(fmt.Sprintf("You have %d seconds to post your link, %s", int(grace.Seconds()), username))
+}
+
+// handlePermitCommand parses the "!permit <user> [seconds]" argument list.
+func (bot *Bot) handlePermitCommand(args []string) {
+	if len(args) < 1 {
+		bot.Message("Usage: !permit <user> [seconds]")
+		return
+	}
+
+	var grace time.Duration
+	if len(args) > 1 {
+		if n, err := strconv.Atoi(args[1]); err == nil {
+			grace = time.Duration(n) * time.Second
+		}
+	}
+	bot.Permit(args[0], grace)
+}